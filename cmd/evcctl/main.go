@@ -0,0 +1,194 @@
+// Command evcctl applies EVC (Enhanced vMotion Compatibility) modes to
+// vSphere virtual machines using pkg/evc.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/sneal/govmomi-playground/pkg/evc"
+)
+
+func main() {
+	dc := flag.String("dc", "", "vSphere datacenter")
+	cluster := flag.String("cluster", "", "vSphere cluster")
+	vmName := flag.String("vm", "", "VM name")
+	inventoryPath := flag.String("inventory", "", "inventory path of a datacenter, cluster, or folder to apply the EVC mode to every VM beneath it, instead of a single -vm")
+	concurrency := flag.Int("concurrency", 4, "number of VMs to process at once when -inventory is set")
+	evcMode := flag.String("evcmode", "intel-sandybridge", "EVC mode, i.e. intel-sandybridge")
+	dryRun := flag.Bool("dry-run", false, "report the EVC feature mask delta without applying it")
+	format := flag.String("format", "text", "feature mask report format: text, json, yaml, or ruby-vim")
+	flag.Parse()
+
+	if *inventoryPath == "" && (*dc == "" || *cluster == "" || *vmName == "") {
+		fmt.Println("dc, cluster, and vm are required flags, unless -inventory is set")
+		os.Exit(1)
+	}
+
+	haveUserPass := os.Getenv("GOVC_USERNAME") != "" && os.Getenv("GOVC_PASSWORD") != ""
+	haveCert := os.Getenv("GOVC_CERTIFICATE") != "" && os.Getenv("GOVC_PRIVATE_KEY") != ""
+	if os.Getenv("GOVC_URL") == "" || (!haveUserPass && !haveCert) {
+		fmt.Println("GOVC_URL is required, along with either GOVC_USERNAME/GOVC_PASSWORD or GOVC_CERTIFICATE/GOVC_PRIVATE_KEY")
+		os.Exit(1)
+	}
+
+	formatter, err := evc.ParseFormat(*format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	if *inventoryPath != "" {
+		err = runInventory(ctx, *inventoryPath, *evcMode, *concurrency, *dryRun, formatter)
+	} else {
+		err = run(ctx, *dc, *cluster, *evcMode, *vmName, *dryRun, formatter)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func runInventory(ctx context.Context, path, evcMode string, concurrency int, dryRun bool, formatter evc.Formatter) error {
+	c, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	mgr := evc.NewManager(c.Client)
+	results, err := mgr.ApplyModeToInventory(ctx, path, evcMode, concurrency, evc.ApplyOptions{DryRun: dryRun, Format: formatter})
+	if err != nil {
+		return err
+	}
+
+	var all []evc.VMResult
+	for r := range results {
+		switch {
+		case r.Report != nil:
+			if err := formatter.Format(os.Stdout, r.Report); err != nil {
+				return err
+			}
+		case r.Status == evc.StatusSucceeded:
+			_, _ = fmt.Fprintf(os.Stderr, "%s: applied %s\n", r.VM, evcMode)
+		case r.Status == evc.StatusSkippedPoweredOn:
+			_, _ = fmt.Fprintf(os.Stderr, "%s: skipped, powered on\n", r.VM)
+		case r.Status == evc.StatusFailed:
+			_, _ = fmt.Fprintf(os.Stderr, "%s: failed: %s\n", r.VM, r.Err)
+		}
+		all = append(all, r)
+	}
+
+	summary := evc.Summarize(all)
+	_, _ = fmt.Fprintf(os.Stderr, "\n%d succeeded, %d skipped (powered on), %d failed\n", summary.Succeeded, summary.SkippedPoweredOn, summary.Failed)
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d VMs failed to apply EVC mode %s", summary.Failed, evcMode)
+	}
+	return nil
+}
+
+func run(ctx context.Context, dc, cluster, evcMode, vmName string, dryRun bool, formatter evc.Formatter) error {
+	c, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	finder := find.NewFinder(c.Client)
+	dcObj, err := finder.Datacenter(ctx, dc)
+	if err != nil {
+		return err
+	}
+	finder.SetDatacenter(dcObj)
+
+	vm, err := finder.VirtualMachine(ctx, vmName)
+	if err != nil {
+		return err
+	}
+
+	clusterObj, err := finder.ClusterComputeResourceOrDefault(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	mgr := evc.NewManager(c.Client)
+	if !dryRun {
+		_, _ = fmt.Fprintf(os.Stderr, "Applying EVC mode %s to %s\n", evcMode, vmName)
+	}
+	return mgr.ApplyModeToVM(ctx, vm, clusterObj, evcMode, evc.ApplyOptions{DryRun: dryRun, Format: formatter})
+}
+
+// createClient builds a govmomi client using a session cached on disk, à la
+// govc's GOVC_PERSIST_SESSION, so that repeated invocations of evcctl don't
+// each pay for a full SOAP login. Auth is either username/password
+// (GOVC_USERNAME/GOVC_PASSWORD) or an extension/solution-user client
+// certificate (GOVC_CERTIFICATE/GOVC_PRIVATE_KEY).
+func createClient(ctx context.Context) (*govmomi.Client, error) {
+	u := &url.URL{
+		Scheme: "https",
+		Host:   os.Getenv("GOVC_URL"),
+		Path:   "/sdk",
+	}
+
+	certFile := os.Getenv("GOVC_CERTIFICATE")
+	keyFile := os.Getenv("GOVC_PRIVATE_KEY")
+	if certFile == "" || keyFile == "" {
+		u.User = url.UserPassword(os.Getenv("GOVC_USERNAME"), os.Getenv("GOVC_PASSWORD"))
+	}
+
+	// configureTLS is passed to cache.Session.Login so the CA certs, known
+	// hosts thumbprints, and client certificate are applied to the actual
+	// soap.Client the session builds on a cache miss, not a throwaway one
+	// that gets discarded once a cached session exists.
+	configureTLS := func(sc *soap.Client) error {
+		if caCerts := os.Getenv("GOVC_TLS_CA_CERTS"); caCerts != "" {
+			if err := sc.SetRootCAs(caCerts); err != nil {
+				return fmt.Errorf("could not load GOVC_TLS_CA_CERTS %q: %w", caCerts, err)
+			}
+		}
+
+		if knownHosts := os.Getenv("GOVC_TLS_KNOWN_HOSTS"); knownHosts != "" {
+			if err := sc.LoadThumbprints(knownHosts); err != nil {
+				return fmt.Errorf("could not load GOVC_TLS_KNOWN_HOSTS %q: %w", knownHosts, err)
+			}
+		}
+
+		if certFile != "" && keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return fmt.Errorf("could not load GOVC_CERTIFICATE/GOVC_PRIVATE_KEY: %w", err)
+			}
+			sc.SetCertificate(cert)
+		}
+
+		return nil
+	}
+
+	insecure, _ := strconv.ParseBool(os.Getenv("GOVC_INSECURE"))
+	s := &cache.Session{
+		URL:      u,
+		Insecure: insecure,
+	}
+
+	vimClient := new(vim25.Client)
+	if err := s.Login(ctx, vimClient, configureTLS); err != nil {
+		return nil, fmt.Errorf("could not login via cached session manager, did you set GOVC_USERNAME, GOVC_PASSWORD, GOVC_URL?: %w", err)
+	}
+
+	c := &govmomi.Client{
+		Client:         vimClient,
+		SessionManager: session.NewManager(vimClient),
+	}
+	return c, nil
+}
@@ -0,0 +1,122 @@
+package evc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// FeatureChange describes what applying an EVC mode would do to a single
+// CPUID feature leaf on a VM.
+type FeatureChange string
+
+const (
+	FeatureAdded     FeatureChange = "added"
+	FeatureRemoved   FeatureChange = "removed"
+	FeatureChanged   FeatureChange = "changed"
+	FeatureUnchanged FeatureChange = "unchanged"
+)
+
+// FeatureDiff is the delta for a single feature key between a VM's current
+// feature requirements and a target EVC mode's feature masks.
+type FeatureDiff struct {
+	Key         string
+	FeatureName string
+	Current     string
+	Target      string
+	Change      FeatureChange
+}
+
+// ModeDiff is the result of diffing a VM's current feature requirements
+// against a target EVC mode.
+type ModeDiff struct {
+	VM        string
+	Mode      string
+	PoweredOn bool
+	Features  []FeatureDiff
+
+	// CurrentFeatureRequirement and TargetFeatureMask are the raw inputs to
+	// the diff, as reported by the property collector and the cluster's
+	// supported EVC mode list respectively.
+	CurrentFeatureRequirement []types.VirtualMachineFeatureRequirement
+	TargetFeatureMask         []types.HostFeatureMask
+}
+
+// DiffModeForVM fetches vm's current Runtime.FeatureRequirement via the
+// property collector and diffs it against mode's feature masks,
+// without mutating vm. It also reports whether vm is powered on, since
+// ApplyEvcModeVM_Task requires the VM to be powered off.
+func (m *Manager) DiffModeForVM(ctx context.Context, vm *object.VirtualMachine, cluster *object.ClusterComputeResource, mode string) (*ModeDiff, error) {
+	target, err := m.featureMasksForMode(ctx, cluster, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var o mo.VirtualMachine
+	pc := property.DefaultCollector(m.client)
+	if err := pc.RetrieveOne(ctx, vm.Reference(), []string{"name", "runtime.featureRequirement", "runtime.powerState"}, &o); err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]types.VirtualMachineFeatureRequirement, len(o.Runtime.FeatureRequirement))
+	for _, r := range o.Runtime.FeatureRequirement {
+		current[r.Key] = r
+	}
+
+	seen := make(map[string]bool, len(target))
+	diff := &ModeDiff{
+		VM:                        o.Name,
+		Mode:                      mode,
+		PoweredOn:                 o.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn,
+		CurrentFeatureRequirement: o.Runtime.FeatureRequirement,
+		TargetFeatureMask:         target,
+	}
+
+	for _, t := range target {
+		seen[t.Key] = true
+		c, ok := current[t.Key]
+		switch {
+		case !ok:
+			diff.Features = append(diff.Features, FeatureDiff{Key: t.Key, FeatureName: t.FeatureName, Target: t.Value, Change: FeatureAdded})
+		case c.Value != t.Value:
+			diff.Features = append(diff.Features, FeatureDiff{Key: t.Key, FeatureName: t.FeatureName, Current: c.Value, Target: t.Value, Change: FeatureChanged})
+		default:
+			diff.Features = append(diff.Features, FeatureDiff{Key: t.Key, FeatureName: t.FeatureName, Current: c.Value, Target: t.Value, Change: FeatureUnchanged})
+		}
+	}
+
+	// current is a map, so iteration order is random; collect the removed
+	// features separately and sort by Key so repeated runs against
+	// unchanged state produce byte-identical Reports.
+	var removed []FeatureDiff
+	for key, c := range current {
+		if !seen[key] {
+			removed = append(removed, FeatureDiff{Key: key, FeatureName: c.FeatureName, Current: c.Value, Change: FeatureRemoved})
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Key < removed[j].Key })
+	diff.Features = append(diff.Features, removed...)
+
+	return diff, nil
+}
+
+// Fprint writes d as a human-readable table of feature changes to w.
+func (d *ModeDiff) Fprint(w io.Writer) {
+	if d.PoweredOn {
+		fmt.Fprintf(w, "warning: %s is powered on; ApplyEvcModeVM_Task requires the VM to be powered off\n", d.VM)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "KEY\tFEATURE\tCURRENT\tTARGET\tCHANGE\n")
+	for _, f := range d.Features {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.Key, f.FeatureName, f.Current, f.Target, f.Change)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,175 @@
+// Package evc provides a reusable client for inspecting and applying
+// vSphere EVC (Enhanced vMotion Compatibility) modes to clusters and
+// virtual machines, built on top of govmomi.
+package evc
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Manager provides EVC-related operations against a vSphere inventory.
+type Manager struct {
+	client *vim25.Client
+}
+
+// NewManager returns a Manager that issues requests using c.
+func NewManager(c *vim25.Client) *Manager {
+	return &Manager{client: c}
+}
+
+// ApplyOptions controls how ApplyModeToVM applies an EVC mode.
+type ApplyOptions struct {
+	// DryRun, if set, reports the feature mask delta ApplyModeToVM would
+	// make instead of mutating the VM.
+	DryRun bool
+
+	// Format renders the feature mask report. Defaults to a human-readable
+	// table.
+	Format Formatter
+}
+
+// clusterEVCManager fetches the mo.ClusterEVCManager for cluster.
+func (m *Manager) clusterEVCManager(ctx context.Context, cluster *object.ClusterComputeResource) (*mo.ClusterEVCManager, error) {
+	res, err := methods.EvcManager(ctx, m.client.RoundTripper, &types.EvcManager{
+		This: cluster.Reference(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var evcMgr mo.ClusterEVCManager
+	pc := property.DefaultCollector(m.client)
+	if err := pc.RetrieveOne(ctx, *res.Returnval, nil, &evcMgr); err != nil {
+		return nil, err
+	}
+	return &evcMgr, nil
+}
+
+// ListSupportedModes returns the EVC modes supported by cluster.
+func (m *Manager) ListSupportedModes(ctx context.Context, cluster *object.ClusterComputeResource) ([]types.EVCMode, error) {
+	evcMgr, err := m.clusterEVCManager(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	return evcMgr.EvcState.SupportedEVCMode, nil
+}
+
+// featureMasksForMode returns the feature masks for the named EVC mode, as
+// reported by cluster's supported mode list.
+func (m *Manager) featureMasksForMode(ctx context.Context, cluster *object.ClusterComputeResource, mode string) ([]types.HostFeatureMask, error) {
+	modes, err := m.ListSupportedModes(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range modes {
+		if e.ElementDescription.Key == mode {
+			return e.FeatureMask, nil
+		}
+	}
+	return nil, fmt.Errorf("error finding EVC feature masks for %s", mode)
+}
+
+// clusterForVM returns the cluster that owns vm.
+func (m *Manager) clusterForVM(ctx context.Context, vm *object.VirtualMachine) (*object.ClusterComputeResource, error) {
+	pool, err := vm.ResourcePool(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var poolMo mo.ResourcePool
+	pc := property.DefaultCollector(m.client)
+	if err := pc.RetrieveOne(ctx, pool.Reference(), []string{"owner"}, &poolMo); err != nil {
+		return nil, err
+	}
+
+	return object.NewClusterComputeResource(m.client, poolMo.Owner), nil
+}
+
+// GetVMEvcMode returns the current EVC mode key of the cluster that owns vm.
+// VMs don't carry an EVC mode of their own; they run under whatever mode is
+// current on their cluster.
+func (m *Manager) GetVMEvcMode(ctx context.Context, vm *object.VirtualMachine) (string, error) {
+	clusterObj, err := m.clusterForVM(ctx, vm)
+	if err != nil {
+		return "", err
+	}
+
+	evcMgr, err := m.clusterEVCManager(ctx, clusterObj)
+	if err != nil {
+		return "", err
+	}
+	return evcMgr.EvcState.CurrentEVCModeKey, nil
+}
+
+// ApplyModeToVM applies mode to vm via ApplyEvcModeVM_Task, using the
+// feature masks defined by cluster's supported EVC mode list. It always
+// renders the feature mask delta with opts.Format first; if opts.DryRun is
+// set, it stops there instead of mutating vm.
+func (m *Manager) ApplyModeToVM(ctx context.Context, vm *object.VirtualMachine, cluster *object.ClusterComputeResource, mode string, opts ApplyOptions) error {
+	diff, err := m.DiffModeForVM(ctx, vm, cluster, mode)
+	if err != nil {
+		return err
+	}
+
+	formatter := opts.Format
+	if formatter == nil {
+		formatter = textFormatter{}
+	}
+	if err := formatter.Format(os.Stdout, reportFromDiff(cluster.InventoryPath, diff)); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	return m.applyMasks(ctx, vm, diff.TargetFeatureMask)
+}
+
+// applyMasks applies masks to vm via ApplyEvcModeVM_Task and waits for the
+// resulting task to complete.
+func (m *Manager) applyMasks(ctx context.Context, vm *object.VirtualMachine, masks []types.HostFeatureMask) error {
+	isComplete := true
+	req := types.ApplyEvcModeVM_Task{
+		This:          vm.Reference(),
+		Mask:          masks,
+		CompleteMasks: &isComplete,
+	}
+
+	res, err := methods.ApplyEvcModeVM_Task(ctx, m.client, &req)
+	if err != nil {
+		return err
+	}
+
+	return object.NewTask(m.client, res.Returnval).Wait(ctx)
+}
+
+// ApplyModeToCluster applies mode as the cluster's own current EVC mode via
+// ConfigureEvcMode_Task, changing the baseline new VMs and vMotions into the
+// cluster will be held to.
+func (m *Manager) ApplyModeToCluster(ctx context.Context, cluster *object.ClusterComputeResource, mode string) error {
+	evcMgr, err := m.clusterEVCManager(ctx, cluster)
+	if err != nil {
+		return err
+	}
+
+	res, err := methods.ConfigureEvcMode_Task(ctx, m.client.RoundTripper, &types.ConfigureEvcMode_Task{
+		This:       evcMgr.Self,
+		EvcModeKey: mode,
+	})
+	if err != nil {
+		return err
+	}
+
+	return object.NewTask(m.client, res.Returnval).Wait(ctx)
+}
@@ -0,0 +1,188 @@
+package evc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// testEVCMode is the single EVC mode evcClusterComputeResource advertises as
+// supported, so the test has a mode to diff and apply.
+const testEVCMode = "test-evc-mode"
+
+// evcClusterComputeResource extends the simulator's ClusterComputeResource
+// with EvcManager, which vcsim doesn't implement out of the box. It
+// registers a ClusterEVCManager advertising testEVCMode so
+// clusterEVCManager has something to retrieve.
+type evcClusterComputeResource struct {
+	simulator.ClusterComputeResource
+}
+
+func (c *evcClusterComputeResource) EvcManager(ctx *simulator.Context, req *types.EvcManager) soap.HasFault {
+	evcMgr := &mo.ClusterEVCManager{
+		ManagedCluster: c.Self,
+		EvcState: types.ClusterEVCManagerEVCState{
+			SupportedEVCMode: []types.EVCMode{
+				{
+					ElementDescription: types.ElementDescription{Key: testEVCMode},
+					FeatureMask: []types.HostFeatureMask{
+						{Key: "cpuid.SSE3", FeatureName: "cpuid.SSE3", Value: "Supported"},
+					},
+				},
+			},
+		},
+	}
+	ctx.Map.Put(evcMgr)
+
+	ref := evcMgr.Self
+	return &methods.EvcManagerBody{
+		Res: &types.EvcManagerResponse{Returnval: &ref},
+	}
+}
+
+// evcVirtualMachine extends the simulator's VirtualMachine with
+// ApplyEvcModeVM_Task, which vcsim doesn't implement out of the box. It
+// records the requested Mask/CompleteMasks so the test can assert the SOAP
+// request body is well-formed without a real vCenter.
+type evcVirtualMachine struct {
+	simulator.VirtualMachine
+
+	lastMask     []types.HostFeatureMask
+	lastComplete *bool
+}
+
+func (vm *evcVirtualMachine) ApplyEvcModeVMTask(ctx *simulator.Context, req *types.ApplyEvcModeVM_Task) soap.HasFault {
+	vm.lastMask = req.Mask
+	vm.lastComplete = req.CompleteMasks
+
+	task := simulator.CreateTask(vm, "applyEvcModeVM", func(*simulator.Task) (types.AnyType, types.BaseMethodFault) {
+		return nil, nil
+	})
+
+	return &methods.ApplyEvcModeVM_TaskBody{
+		Res: &types.ApplyEvcModeVM_TaskResponse{Returnval: task.Run(ctx)},
+	}
+}
+
+// setup starts a vcsim VPX model with a cluster and VM, and swaps the VM's
+// and cluster's simulator objects for evcVirtualMachine and
+// evcClusterComputeResource so ApplyEvcModeVM_Task and EvcManager are
+// handled.
+func setup(t *testing.T) (context.Context, *Manager, func()) {
+	t.Helper()
+
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("model.Create: %s", err)
+	}
+
+	server := model.Service.NewServer()
+	ctx := context.Background()
+
+	client, err := govmomi.NewClient(ctx, server.URL, true)
+	if err != nil {
+		model.Remove()
+		server.Close()
+		t.Fatalf("govmomi.NewClient: %s", err)
+	}
+
+	finder := find.NewFinder(client.Client)
+	dc, err := finder.DefaultDatacenter(ctx)
+	if err != nil {
+		t.Fatalf("DefaultDatacenter: %s", err)
+	}
+	finder.SetDatacenter(dc)
+
+	vms, err := finder.VirtualMachineList(ctx, "*")
+	if err != nil || len(vms) == 0 {
+		t.Fatalf("VirtualMachineList: %v (err=%s)", vms, err)
+	}
+	vm := vms[0]
+
+	ref := vm.Reference()
+	orig := simulator.Map.Get(ref).(*simulator.VirtualMachine)
+	simulator.Map.Put(&evcVirtualMachine{VirtualMachine: *orig})
+
+	clusters, err := finder.ClusterComputeResourceList(ctx, "*")
+	if err != nil || len(clusters) == 0 {
+		t.Fatalf("ClusterComputeResourceList: %v (err=%s)", clusters, err)
+	}
+	clusterRef := clusters[0].Reference()
+	origCluster := simulator.Map.Get(clusterRef).(*simulator.ClusterComputeResource)
+	simulator.Map.Put(&evcClusterComputeResource{ClusterComputeResource: *origCluster})
+
+	return ctx, NewManager(client.Client), func() {
+		server.Close()
+		model.Remove()
+	}
+}
+
+func TestEVCWorkflow(t *testing.T) {
+	ctx, mgr, teardown := setup(t)
+	defer teardown()
+
+	finder := find.NewFinder(mgr.client)
+	dc, err := finder.DefaultDatacenter(ctx)
+	if err != nil {
+		t.Fatalf("DefaultDatacenter: %s", err)
+	}
+	finder.SetDatacenter(dc)
+
+	cluster, err := finder.DefaultClusterComputeResource(ctx)
+	if err != nil {
+		t.Fatalf("DefaultClusterComputeResource: %s", err)
+	}
+
+	vms, err := finder.VirtualMachineList(ctx, "*")
+	if err != nil || len(vms) == 0 {
+		t.Fatalf("VirtualMachineList: %v (err=%s)", vms, err)
+	}
+	vm := vms[0]
+
+	modes, err := mgr.ListSupportedModes(ctx, cluster)
+	if err != nil {
+		t.Fatalf("ListSupportedModes: %s", err)
+	}
+	if len(modes) == 0 {
+		t.Fatal("expected at least one supported EVC mode")
+	}
+	mode := modes[0].ElementDescription.Key
+
+	diff, err := mgr.DiffModeForVM(ctx, vm, cluster, mode)
+	if err != nil {
+		t.Fatalf("DiffModeForVM: %s", err)
+	}
+	if len(diff.Features) == 0 {
+		t.Fatal("expected at least one feature in the diff")
+	}
+	for _, f := range diff.Features {
+		if f.Change != FeatureAdded {
+			t.Fatalf("expected a VM with no prior feature requirements to only have added features, got %s for %s", f.Change, f.Key)
+		}
+	}
+
+	if err := mgr.ApplyModeToVM(ctx, vm, cluster, mode, ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyModeToVM: %s", err)
+	}
+
+	masks, err := mgr.featureMasksForMode(ctx, cluster, mode)
+	if err != nil {
+		t.Fatalf("featureMasksForMode: %s", err)
+	}
+
+	custom := simulator.Map.Get(vm.Reference()).(*evcVirtualMachine)
+	if !reflect.DeepEqual(custom.lastMask, masks) {
+		t.Fatalf("ApplyEvcModeVM_Task request Mask = %+v, want %+v", custom.lastMask, masks)
+	}
+	if custom.lastComplete == nil || !*custom.lastComplete {
+		t.Fatal("ApplyEvcModeVM_Task request CompleteMasks = false or nil, want true")
+	}
+}
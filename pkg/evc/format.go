@@ -0,0 +1,110 @@
+package evc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/vmware/govmomi/vim25/types"
+	"gopkg.in/yaml.v2"
+)
+
+// Report is the machine-readable result of diffing a VM against a target
+// EVC mode, suitable for consumption by automation (Ansible, Jenkins,
+// Argo, ...) instead of regex-parsing debug output.
+type Report struct {
+	Cluster                   string                                   `json:"cluster" yaml:"cluster"`
+	Mode                      string                                   `json:"mode" yaml:"mode"`
+	VM                        string                                   `json:"vm" yaml:"vm"`
+	PoweredOn                 bool                                     `json:"poweredOn" yaml:"poweredOn"`
+	TargetFeatureMask         []types.HostFeatureMask                  `json:"targetFeatureMask" yaml:"targetFeatureMask"`
+	CurrentFeatureRequirement []types.VirtualMachineFeatureRequirement `json:"currentFeatureRequirement" yaml:"currentFeatureRequirement"`
+	Diff                      []FeatureDiff                            `json:"diff" yaml:"diff"`
+}
+
+// reportFromDiff builds a Report from a ModeDiff and the cluster it was
+// computed against.
+func reportFromDiff(cluster string, d *ModeDiff) *Report {
+	return &Report{
+		Cluster:                   cluster,
+		Mode:                      d.Mode,
+		VM:                        d.VM,
+		PoweredOn:                 d.PoweredOn,
+		TargetFeatureMask:         d.TargetFeatureMask,
+		CurrentFeatureRequirement: d.CurrentFeatureRequirement,
+		Diff:                      d.Features,
+	}
+}
+
+// Formatter renders a Report to w.
+type Formatter interface {
+	Format(w io.Writer, r *Report) error
+}
+
+// textFormatter renders a Report as a human-readable table, the same shape
+// ModeDiff.Fprint has always produced.
+type textFormatter struct{}
+
+func (textFormatter) Format(w io.Writer, r *Report) error {
+	d := &ModeDiff{
+		VM:        r.VM,
+		Mode:      r.Mode,
+		PoweredOn: r.PoweredOn,
+		Features:  r.Diff,
+	}
+	d.Fprint(w)
+	return nil
+}
+
+// jsonFormatter renders a Report as indented JSON.
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// yamlFormatter renders a Report as YAML.
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, r *Report) error {
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// rubyVimFormatter renders a Report's target feature mask as the
+// VimSdk::Vim::Host::FeatureMask snippet this tool originally printed, for
+// pasting into Ruby vSphere SDK scripts.
+type rubyVimFormatter struct{}
+
+func (rubyVimFormatter) Format(w io.Writer, r *Report) error {
+	for _, mask := range r.TargetFeatureMask {
+		_, err := fmt.Fprintf(w, "mask << VimSdk::Vim::Host::FeatureMask.new({:key => \"%s\", :feature_name => \"%s\", :value => \"%s\"})\n", mask.Key, mask.FeatureName, mask.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseFormat returns the Formatter named by format: "text", "json",
+// "yaml", or "ruby-vim".
+func ParseFormat(format string) (Formatter, error) {
+	switch format {
+	case "", "text":
+		return textFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "yaml":
+		return yamlFormatter{}, nil
+	case "ruby-vim":
+		return rubyVimFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, want one of: text, json, yaml, ruby-vim", format)
+	}
+}
@@ -0,0 +1,147 @@
+package evc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// VMStatus is the outcome of applying an EVC mode to a single VM as part of
+// ApplyModeToInventory.
+type VMStatus string
+
+const (
+	StatusSucceeded        VMStatus = "succeeded"
+	StatusSkippedPoweredOn VMStatus = "skipped-powered-on"
+	StatusFailed           VMStatus = "failed"
+)
+
+// VMResult is the outcome of applying an EVC mode to one VM, or of diffing
+// it against mode when ApplyOptions.DryRun is set via ApplyModeToInventory.
+type VMResult struct {
+	VM     string
+	Status VMStatus
+	Err    error
+
+	// Report is set instead of Err/a plain Status message when DryRun was
+	// set, so the caller can render it with a Formatter.
+	Report *Report
+}
+
+// Summary tallies a batch of VMResults.
+type Summary struct {
+	Succeeded        int
+	SkippedPoweredOn int
+	Failed           int
+	Results          []VMResult
+}
+
+// Summarize tallies results into a Summary.
+func Summarize(results []VMResult) Summary {
+	var s Summary
+	for _, r := range results {
+		switch r.Status {
+		case StatusSucceeded:
+			s.Succeeded++
+		case StatusSkippedPoweredOn:
+			s.SkippedPoweredOn++
+		case StatusFailed:
+			s.Failed++
+		}
+		s.Results = append(s.Results, r)
+	}
+	return s
+}
+
+// ApplyModeToInventory resolves path to a datacenter, cluster, or folder,
+// enumerates every VM beneath it, and applies mode to each, skipping any VM
+// that is powered on since ApplyEvcModeVM_Task requires the VM to be powered
+// off. If opts.DryRun is set, no VM is mutated; each VMResult carries a
+// Report of the feature mask delta instead. Up to concurrency VMs are
+// processed at once. Results are streamed on the returned channel as each VM
+// finishes; the channel is closed once all VMs have been processed.
+func (m *Manager) ApplyModeToInventory(ctx context.Context, path, mode string, concurrency int, opts ApplyOptions) (<-chan VMResult, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	si := object.NewSearchIndex(m.client)
+	ref, err := si.FindByInventoryPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("no inventory object found at path %s", path)
+	}
+
+	mgr := view.NewManager(m.client)
+	cv, err := mgr.CreateContainerView(ctx, ref.Reference(), []string{"VirtualMachine"}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer cv.Destroy(ctx)
+
+	var vms []mo.VirtualMachine
+	if err := cv.Retrieve(ctx, []string{"VirtualMachine"}, []string{"name", "runtime.powerState"}, &vms); err != nil {
+		return nil, err
+	}
+
+	results := make(chan VMResult, len(vms))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	go func() {
+		for _, o := range vms {
+			o := o
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results <- m.applyModeToInventoryVM(ctx, o, mode, opts)
+			}()
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// applyModeToInventoryVM applies mode to the VM described by o, as part of
+// ApplyModeToInventory. If opts.DryRun is set, it reports the feature mask
+// delta instead of mutating the VM, powered on or not.
+func (m *Manager) applyModeToInventoryVM(ctx context.Context, o mo.VirtualMachine, mode string, opts ApplyOptions) VMResult {
+	if !opts.DryRun && o.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+		return VMResult{VM: o.Name, Status: StatusSkippedPoweredOn}
+	}
+
+	vm := object.NewVirtualMachine(m.client, o.Self)
+	cluster, err := m.clusterForVM(ctx, vm)
+	if err != nil {
+		return VMResult{VM: o.Name, Status: StatusFailed, Err: err}
+	}
+
+	if opts.DryRun {
+		diff, err := m.DiffModeForVM(ctx, vm, cluster, mode)
+		if err != nil {
+			return VMResult{VM: o.Name, Status: StatusFailed, Err: err}
+		}
+		return VMResult{VM: o.Name, Status: StatusSucceeded, Report: reportFromDiff(cluster.InventoryPath, diff)}
+	}
+
+	masks, err := m.featureMasksForMode(ctx, cluster, mode)
+	if err != nil {
+		return VMResult{VM: o.Name, Status: StatusFailed, Err: err}
+	}
+
+	if err := m.applyMasks(ctx, vm, masks); err != nil {
+		return VMResult{VM: o.Name, Status: StatusFailed, Err: err}
+	}
+	return VMResult{VM: o.Name, Status: StatusSucceeded}
+}